@@ -0,0 +1,204 @@
+package simplegemini
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"cloud.google.com/go/vertexai/genai"
+	aistudio "github.com/google/generative-ai-go/genai"
+	"github.com/xyproto/env/v2"
+	"google.golang.org/api/option"
+)
+
+// Backend abstracts the Gemini provider a GeminiClient talks to, so that the
+// public Query/MultiQuery/Submit surface behaves the same whether requests
+// go to Vertex AI (the default, needs GCP_PROJECT/ADC) or Google AI Studio
+// (needs only GEMINI_API_KEY). Callers keep working with the Vertex AI
+// genai.Tool/Part/FunctionCall types throughout; backends that talk to a
+// different SDK convert to and from those types at their boundary.
+type Backend interface {
+	// Generate sends parts to the named model, configured per cfg, and
+	// returns its text response.
+	Generate(ctx context.Context, modelName string, cfg GenerationConfig, tools []*genai.Tool, parts []genai.Part) (string, error)
+	// CountTokens counts the tokens parts would use against the named model.
+	CountTokens(ctx context.Context, modelName string, parts []genai.Part) (int, error)
+	// Close releases any resources held by the backend.
+	Close() error
+}
+
+// vertexBackend is the default Backend and wraps an authenticated Vertex AI
+// client. GeminiClient drives *genai.Client directly wherever it needs
+// chat sessions or streaming (see MultiQuery, MultiQueryStream, and
+// NewChatSession), so this
+// implementation only needs to cover the plain request/response path that
+// other backends share.
+type vertexBackend struct {
+	client *genai.Client
+}
+
+func (b *vertexBackend) Generate(ctx context.Context, modelName string, cfg GenerationConfig, tools []*genai.Tool, parts []genai.Part) (string, error) {
+	model := b.client.GenerativeModel(modelName)
+	model.SetTemperature(cfg.Temperature)
+	model.Tools = tools
+	applyGenerationConfigToModel(model, cfg)
+	res, err := model.GenerateContent(ctx, parts...)
+	if err != nil {
+		return "", fmt.Errorf("unable to generate contents: %v", err)
+	}
+	if len(res.Candidates) == 0 || res.Candidates[0].Content == nil || len(res.Candidates[0].Content.Parts) == 0 {
+		return "", fmt.Errorf("empty response from model")
+	}
+	return fmt.Sprintf("%s", res.Candidates[0].Content.Parts[0]), nil
+}
+
+func (b *vertexBackend) CountTokens(ctx context.Context, modelName string, parts []genai.Part) (int, error) {
+	model := b.client.GenerativeModel(modelName)
+	var sum int
+	for _, part := range parts {
+		resp, err := model.CountTokens(ctx, part)
+		if err != nil {
+			return sum, err
+		}
+		sum += int(resp.TotalTokens)
+	}
+	return sum, nil
+}
+
+func (b *vertexBackend) Close() error {
+	return b.client.Close()
+}
+
+// aiStudioBackend talks to Google AI Studio via github.com/google/generative-ai-go/genai,
+// authenticating with an API key instead of a GCP project and Application
+// Default Credentials. It only supports text and inline-data parts, and
+// Temperature/TopP/TopK/MaxOutputTokens/StopSequences/CandidateCount from
+// GenerationConfig; JSON mode, SafetySettings, and Tools (function calling)
+// are rejected with an explicit error, since chat sessions, streaming, and
+// function-call dispatch are not wired up to it yet, so GeminiClient falls
+// back to a single-shot Generate call for clients constructed via
+// NewWithAPIKey.
+type aiStudioBackend struct {
+	client *aistudio.Client
+}
+
+func newAIStudioBackend(ctx context.Context, apiKey string) (*aiStudioBackend, error) {
+	client, err := aistudio.NewClient(ctx, option.WithAPIKey(apiKey))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AI Studio genai client: %v", err)
+	}
+	return &aiStudioBackend{client: client}, nil
+}
+
+func (b *aiStudioBackend) Generate(ctx context.Context, modelName string, cfg GenerationConfig, tools []*genai.Tool, parts []genai.Part) (string, error) {
+	if cfg.ResponseMIMEType != "" || cfg.ResponseSchema != nil {
+		return "", fmt.Errorf("JSON mode (ResponseMIMEType/ResponseSchema) is not supported by the AI Studio backend yet")
+	}
+	if len(cfg.SafetySettings) > 0 {
+		return "", fmt.Errorf("SafetySettings are not supported by the AI Studio backend yet")
+	}
+	if len(tools) > 0 {
+		return "", fmt.Errorf("function calling (Tools) is not supported by the AI Studio backend yet")
+	}
+
+	model := b.client.GenerativeModel(modelName)
+	model.SetTemperature(cfg.Temperature)
+	if cfg.TopP != nil {
+		model.SetTopP(*cfg.TopP)
+	}
+	if cfg.TopK != nil {
+		model.SetTopK(*cfg.TopK)
+	}
+	if cfg.MaxOutputTokens != nil {
+		model.SetMaxOutputTokens(*cfg.MaxOutputTokens)
+	}
+	if len(cfg.StopSequences) > 0 {
+		model.StopSequences = cfg.StopSequences
+	}
+	if cfg.CandidateCount != nil {
+		model.SetCandidateCount(*cfg.CandidateCount)
+	}
+
+	aiParts, err := toAIStudioParts(parts)
+	if err != nil {
+		return "", err
+	}
+	res, err := model.GenerateContent(ctx, aiParts...)
+	if err != nil {
+		return "", fmt.Errorf("unable to generate contents: %v", err)
+	}
+	if len(res.Candidates) == 0 || res.Candidates[0].Content == nil || len(res.Candidates[0].Content.Parts) == 0 {
+		return "", fmt.Errorf("empty response from model")
+	}
+	return fmt.Sprintf("%s", res.Candidates[0].Content.Parts[0]), nil
+}
+
+func (b *aiStudioBackend) CountTokens(ctx context.Context, modelName string, parts []genai.Part) (int, error) {
+	model := b.client.GenerativeModel(modelName)
+	aiParts, err := toAIStudioParts(parts)
+	if err != nil {
+		return 0, err
+	}
+	var sum int
+	for _, part := range aiParts {
+		resp, err := model.CountTokens(ctx, part)
+		if err != nil {
+			return sum, err
+		}
+		sum += int(resp.TotalTokens)
+	}
+	return sum, nil
+}
+
+func (b *aiStudioBackend) Close() error {
+	return b.client.Close()
+}
+
+// toAIStudioParts adapts genai.Part values from the Vertex AI SDK (the type
+// used throughout GeminiClient's public API) to their
+// github.com/google/generative-ai-go/genai equivalents, so that callers
+// never need to import the AI Studio SDK directly.
+func toAIStudioParts(parts []genai.Part) ([]aistudio.Part, error) {
+	out := make([]aistudio.Part, 0, len(parts))
+	for _, part := range parts {
+		switch p := part.(type) {
+		case genai.Text:
+			out = append(out, aistudio.Text(p))
+		case genai.Blob:
+			out = append(out, aistudio.Blob{MIMEType: p.MIMEType, Data: p.Data})
+		default:
+			return nil, fmt.Errorf("AI Studio backend does not support part type %T", part)
+		}
+	}
+	return out, nil
+}
+
+// NewWithAPIKey creates a GeminiClient backed by Google AI Studio instead of
+// Vertex AI, authenticating with apiKey (or $GEMINI_API_KEY) instead of a
+// GCP project and Application Default Credentials. This unblocks users who
+// have a free Gemini API key but no GCP project to point Vertex AI at.
+func NewWithAPIKey(modelName, apiKey string, temperature float32) (*GeminiClient, error) {
+	apiKey = env.Str("GEMINI_API_KEY", apiKey)
+	if apiKey == "" {
+		return nil, ErrGeminiAPIKey
+	}
+	backend, err := newAIStudioBackend(context.Background(), apiKey)
+	if err != nil {
+		return nil, err
+	}
+	return &GeminiClient{
+		backend:           backend,
+		ModelName:         env.Str("MODEL_NAME", modelName),
+		Timeout:           defaultTimeout,
+		Temperature:       temperature,
+		Tools:             []*genai.Tool{},
+		Functions:         make(map[string]reflect.Value),
+		Trim:              defaultTrim,
+		Verbose:           defaultVerbose,
+		Parts:             make([]genai.Part, 0),
+		MaxParallelCalls:  defaultMaxParallelCalls,
+		MaxToolIterations: defaultMaxToolIterations,
+		CallTimeout:       defaultCallTimeout,
+		RetryPolicy:       DefaultRetryPolicy,
+	}, nil
+}
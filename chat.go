@@ -0,0 +1,144 @@
+package simplegemini
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"cloud.google.com/go/vertexai/genai"
+)
+
+// ChatSession wraps a genai.ChatSession so that tool/function registrations
+// and conversation history stay alive across multiple turns. MultiQuery, by
+// contrast, calls model.StartChat() fresh on every invocation and throws the
+// history away once that single query's function-call round trips are done.
+type ChatSession struct {
+	gc      *GeminiClient
+	model   *genai.GenerativeModel
+	session *genai.ChatSession
+}
+
+// NewChatSession starts a new multi-turn conversation using gc's model,
+// temperature, and registered tools/functions. Chat sessions are not
+// implemented for backends other than Vertex AI yet, so it returns an error
+// if gc was constructed via NewWithAPIKey.
+func (gc *GeminiClient) NewChatSession() (*ChatSession, error) {
+	if gc.Client == nil {
+		return nil, fmt.Errorf("chat sessions are not supported by this backend")
+	}
+	model := gc.Client.GenerativeModel(gc.ModelName)
+	model.SetTemperature(gc.Temperature)
+	model.Tools = gc.Tools
+	gc.applyGenerationConfig(model)
+	return &ChatSession{
+		gc:      gc,
+		model:   model,
+		session: model.StartChat(),
+	}, nil
+}
+
+// Send submits prompt as the next turn in the conversation and returns the
+// model's text response. If the model responds with one or more FunctionCall
+// parts, all of them are dispatched via the reflection-based dispatcher and
+// the resulting FunctionResponse values are batched back before the final
+// text is returned.
+func (cs *ChatSession) Send(prompt string) (string, error) {
+	return cs.SendMultimodal(genai.Text(prompt))
+}
+
+// SendMultimodal submits one or more parts as the next turn in the
+// conversation, which allows combining text with inline data the way
+// MultiQuery does for a single-shot query.
+func (cs *ChatSession) SendMultimodal(parts ...genai.Part) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), cs.gc.Timeout)
+	defer cancel()
+
+	res, err := cs.session.SendMessage(ctx, parts...)
+	if err != nil {
+		return "", fmt.Errorf("failed to send message: %v", err)
+	}
+
+	// Handle function calls if present, the same way MultiQuery does: every
+	// FunctionCall part in a turn is dispatched (concurrently, up to
+	// gc.MaxParallelCalls) before the batched FunctionResponse values are
+	// sent back in a single SendMessage, looping until the model stops
+	// asking for more calls or MaxToolIterations is reached.
+	for iteration := 0; ; iteration++ {
+		funcalls := collectFunctionCalls(res)
+		if len(funcalls) == 0 {
+			break
+		}
+		if iteration >= cs.gc.MaxToolIterations {
+			return "", fmt.Errorf("exceeded MaxToolIterations (%d) while handling function calls", cs.gc.MaxToolIterations)
+		}
+
+		responses, err := cs.gc.invokeFunctions(ctx, funcalls)
+		if err != nil {
+			return "", fmt.Errorf("failed to handle function call: %v", err)
+		}
+		res, err = cs.session.SendMessage(ctx, responses...)
+		if err != nil {
+			return "", fmt.Errorf("failed to send function response: %v", err)
+		}
+	}
+
+	var result strings.Builder
+	if len(res.Candidates) > 0 && res.Candidates[0].Content != nil {
+		for _, part := range res.Candidates[0].Content.Parts {
+			if textPart, ok := part.(genai.Text); ok {
+				result.WriteString(string(textPart))
+			}
+		}
+	}
+	if cs.gc.Trim {
+		return strings.TrimSpace(result.String()), nil
+	}
+	return result.String(), nil
+}
+
+// History returns the conversation so far, in the order the turns occurred.
+func (cs *ChatSession) History() []*genai.Content {
+	return cs.session.History
+}
+
+// SetHistory replaces the conversation history, e.g. with one previously
+// returned by History or loaded via LoadHistory.
+func (cs *ChatSession) SetHistory(history []*genai.Content) {
+	cs.session.History = history
+}
+
+// Reset discards the conversation history, leaving tools and functions
+// registered on the underlying GeminiClient untouched.
+func (cs *ChatSession) Reset() {
+	cs.session.History = nil
+}
+
+// SaveHistory writes the conversation history to path as JSON, so it can be
+// resumed later across program runs via LoadHistory.
+func (cs *ChatSession) SaveHistory(path string) error {
+	data, err := json.Marshal(cs.History())
+	if err != nil {
+		return fmt.Errorf("failed to marshal chat history: %v", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write chat history: %v", err)
+	}
+	return nil
+}
+
+// LoadHistory reads a conversation history previously saved with
+// SaveHistory and installs it as the session's current history.
+func (cs *ChatSession) LoadHistory(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read chat history: %v", err)
+	}
+	var history []*genai.Content
+	if err := json.Unmarshal(data, &history); err != nil {
+		return fmt.Errorf("failed to unmarshal chat history: %v", err)
+	}
+	cs.SetHistory(history)
+	return nil
+}
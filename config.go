@@ -0,0 +1,219 @@
+package simplegemini
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"cloud.google.com/go/vertexai/genai"
+)
+
+// SetTopP sets the nucleus-sampling threshold used for generation.
+func (gc *GeminiClient) SetTopP(topP float32) {
+	gc.TopP = &topP
+}
+
+// SetTopK sets the number of highest-probability tokens considered at each
+// generation step.
+func (gc *GeminiClient) SetTopK(topK int32) {
+	gc.TopK = &topK
+}
+
+// SetMaxOutputTokens caps the number of tokens the model may generate.
+func (gc *GeminiClient) SetMaxOutputTokens(maxOutputTokens int32) {
+	gc.MaxOutputTokens = &maxOutputTokens
+}
+
+// SetStopSequences sets the sequences that, once generated, stop the model
+// from producing further tokens.
+func (gc *GeminiClient) SetStopSequences(stopSequences []string) {
+	gc.StopSequences = stopSequences
+}
+
+// SetCandidateCount sets the number of candidate responses to generate.
+func (gc *GeminiClient) SetCandidateCount(candidateCount int32) {
+	gc.CandidateCount = &candidateCount
+}
+
+// SetResponseMIMEType sets the MIME type of the generated response, e.g.
+// "application/json" for JSON mode.
+func (gc *GeminiClient) SetResponseMIMEType(mimeType string) {
+	gc.ResponseMIMEType = mimeType
+}
+
+// AddSafetySetting registers a category/threshold pair that is sent along
+// with every generation request, e.g. to relax or tighten the default
+// content filters.
+func (gc *GeminiClient) AddSafetySetting(category genai.HarmCategory, threshold genai.HarmBlockThreshold) {
+	gc.SafetySettings = append(gc.SafetySettings, &genai.SafetySetting{
+		Category:  category,
+		Threshold: threshold,
+	})
+}
+
+// SetJSONMode switches the client into structured-output mode: responses are
+// constrained to JSON matching the shape of schema (typically a pointer to,
+// or zero value of, the Go struct the caller wants back) and ResponseSchema
+// is derived from it via reflection. Use QueryJSON to also unmarshal the
+// response in one step.
+func (gc *GeminiClient) SetJSONMode(schema any) error {
+	s, err := schemaFromGoValue(reflect.TypeOf(schema))
+	if err != nil {
+		return fmt.Errorf("failed to derive schema: %v", err)
+	}
+	gc.ResponseMIMEType = "application/json"
+	gc.ResponseSchema = s
+	return nil
+}
+
+// GenerationConfig bundles the GenerationConfig-related settings a
+// GeminiClient can carry, so that they can be threaded through the Backend
+// interface and applied consistently regardless of which provider a
+// request ends up going to.
+type GenerationConfig struct {
+	Temperature      float32
+	TopP             *float32
+	TopK             *int32
+	MaxOutputTokens  *int32
+	StopSequences    []string
+	CandidateCount   *int32
+	ResponseMIMEType string
+	ResponseSchema   *genai.Schema
+	SafetySettings   []*genai.SafetySetting
+}
+
+// generationConfig snapshots gc's GenerationConfig fields, using temperature
+// in place of gc.Temperature (MultiQuery lets a caller override the
+// client's default temperature per call).
+func (gc *GeminiClient) generationConfig(temperature float32) GenerationConfig {
+	return GenerationConfig{
+		Temperature:      temperature,
+		TopP:             gc.TopP,
+		TopK:             gc.TopK,
+		MaxOutputTokens:  gc.MaxOutputTokens,
+		StopSequences:    gc.StopSequences,
+		CandidateCount:   gc.CandidateCount,
+		ResponseMIMEType: gc.ResponseMIMEType,
+		ResponseSchema:   gc.ResponseSchema,
+		SafetySettings:   gc.SafetySettings,
+	}
+}
+
+// applyGenerationConfig copies the GenerationConfig-related fields from gc
+// onto model. It does not touch Temperature: call sites set that
+// separately, since MultiQuery allows overriding it on a per-call basis.
+func (gc *GeminiClient) applyGenerationConfig(model *genai.GenerativeModel) {
+	applyGenerationConfigToModel(model, gc.generationConfig(gc.Temperature))
+}
+
+// applyGenerationConfigToModel applies cfg's fields, other than
+// Temperature, to model. It is shared by GeminiClient's direct
+// *genai.GenerativeModel call sites and by vertexBackend, which drives the
+// same SDK through the Backend interface.
+func applyGenerationConfigToModel(model *genai.GenerativeModel, cfg GenerationConfig) {
+	if cfg.TopP != nil {
+		model.SetTopP(*cfg.TopP)
+	}
+	if cfg.TopK != nil {
+		model.SetTopK(*cfg.TopK)
+	}
+	if cfg.MaxOutputTokens != nil {
+		model.SetMaxOutputTokens(*cfg.MaxOutputTokens)
+	}
+	if len(cfg.StopSequences) > 0 {
+		model.StopSequences = cfg.StopSequences
+	}
+	if cfg.CandidateCount != nil {
+		model.SetCandidateCount(*cfg.CandidateCount)
+	}
+	if cfg.ResponseMIMEType != "" {
+		model.ResponseMIMEType = cfg.ResponseMIMEType
+	}
+	if cfg.ResponseSchema != nil {
+		model.ResponseSchema = cfg.ResponseSchema
+	}
+	if len(cfg.SafetySettings) > 0 {
+		model.SafetySettings = cfg.SafetySettings
+	}
+}
+
+// schemaFromGoValue derives a *genai.Schema describing t, so that a plain Go
+// struct can be used as the desired shape for JSON-mode responses.
+func schemaFromGoValue(t reflect.Type) (*genai.Schema, error) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return &genai.Schema{Type: genai.TypeString}, nil
+	case reflect.Bool:
+		return &genai.Schema{Type: genai.TypeBoolean}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &genai.Schema{Type: genai.TypeInteger}, nil
+	case reflect.Float32, reflect.Float64:
+		return &genai.Schema{Type: genai.TypeNumber}, nil
+	case reflect.Slice, reflect.Array:
+		items, err := schemaFromGoValue(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return &genai.Schema{Type: genai.TypeArray, Items: items}, nil
+	case reflect.Struct:
+		properties := make(map[string]*genai.Schema, t.NumField())
+		var required []string
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.PkgPath != "" { // unexported
+				continue
+			}
+			name := field.Name
+			optional := false
+			if tag, ok := field.Tag.Lookup("json"); ok {
+				tagName, options, _ := strings.Cut(tag, ",")
+				if tagName == "-" {
+					continue
+				}
+				if tagName != "" {
+					name = tagName
+				}
+				for _, option := range strings.Split(options, ",") {
+					if option == "omitempty" {
+						optional = true
+					}
+				}
+			}
+			fieldSchema, err := schemaFromGoValue(field.Type)
+			if err != nil {
+				return nil, fmt.Errorf("field %q: %v", field.Name, err)
+			}
+			properties[name] = fieldSchema
+			if !optional {
+				required = append(required, name)
+			}
+		}
+		return &genai.Schema{Type: genai.TypeObject, Properties: properties, Required: required}, nil
+	default:
+		return nil, fmt.Errorf("unsupported type %s for JSON schema", t)
+	}
+}
+
+// QueryJSON submits prompt in JSON mode and unmarshals the model's response
+// directly into a value of type T, deriving the response schema from T's
+// shape via SetJSONMode.
+func QueryJSON[T any](gc *GeminiClient, prompt string) (T, error) {
+	var zero T
+	if err := gc.SetJSONMode(zero); err != nil {
+		return zero, err
+	}
+	raw, err := gc.Query(prompt)
+	if err != nil {
+		return zero, err
+	}
+	var result T
+	if err := json.Unmarshal([]byte(raw), &result); err != nil {
+		return zero, fmt.Errorf("failed to unmarshal JSON response: %v", err)
+	}
+	return result, nil
+}
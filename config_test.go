@@ -0,0 +1,63 @@
+package simplegemini
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"cloud.google.com/go/vertexai/genai"
+)
+
+type schemaTestStruct struct {
+	Name       string `json:"name"`
+	Nickname   string `json:"nickname,omitempty"`
+	Age        int    `json:"age,omitempty"`
+	Ignored    string `json:"-"`
+	unexported string
+}
+
+func TestSchemaFromGoValueRespectsOmitempty(t *testing.T) {
+	schema, err := schemaFromGoValue(reflect.TypeOf(schemaTestStruct{}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if schema.Type != genai.TypeObject {
+		t.Fatalf("expected object schema, got %v", schema.Type)
+	}
+	if _, ok := schema.Properties["ignored"]; ok {
+		t.Fatalf("field tagged json:\"-\" should not appear in properties")
+	}
+	if _, ok := schema.Properties["-"]; ok {
+		t.Fatalf("field tagged json:\"-\" should not appear in properties")
+	}
+
+	required := append([]string(nil), schema.Required...)
+	sort.Strings(required)
+	want := []string{"name"}
+	if !reflect.DeepEqual(required, want) {
+		t.Fatalf("required = %v, want %v (omitempty fields must not be required)", required, want)
+	}
+}
+
+func TestSchemaFromGoValuePrimitives(t *testing.T) {
+	cases := []struct {
+		value any
+		want  genai.Type
+	}{
+		{"", genai.TypeString},
+		{true, genai.TypeBoolean},
+		{0, genai.TypeInteger},
+		{int32(0), genai.TypeInteger},
+		{0.0, genai.TypeNumber},
+		{[]string{}, genai.TypeArray},
+	}
+	for _, c := range cases {
+		schema, err := schemaFromGoValue(reflect.TypeOf(c.value))
+		if err != nil {
+			t.Fatalf("schemaFromGoValue(%T): unexpected error: %v", c.value, err)
+		}
+		if schema.Type != c.want {
+			t.Fatalf("schemaFromGoValue(%T) = %v, want %v", c.value, schema.Type, c.want)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package simplegemini
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"cloud.google.com/go/vertexai/genai"
+)
+
+// maxInlineFileSize is the largest local file AddFile will inline as
+// base64 data. Larger files must be uploaded to Cloud Storage first and
+// attached via AddFileURI instead, since the Vertex AI Go SDK (unlike the
+// AI Studio one) has no file-upload API of its own.
+const maxInlineFileSize = 20 * 1024 * 1024 // 20 MiB
+
+// AddFileURI attaches a reference to a file already stored in Google Cloud
+// Storage (a gs:// URI), without reading any data into memory. This is the
+// way to pass large images, audio, or video to the model.
+func (gc *GeminiClient) AddFileURI(mimeType, gsURI string) {
+	gc.Parts = append(gc.Parts, genai.FileData{
+		MIMEType: mimeType,
+		FileURI:  gsURI,
+	})
+}
+
+// AddFile attaches a local file to the prompt. Its MIME type is guessed
+// from its extension (falling back to sniffing its contents), and small
+// files are inlined as base64 data via AddData. Files larger than
+// maxInlineFileSize are rejected; upload them to Cloud Storage and use
+// AddFileURI instead.
+func (gc *GeminiClient) AddFile(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to stat file: %v", err)
+	}
+	if info.Size() > maxInlineFileSize {
+		return fmt.Errorf("%s is %d bytes, which is larger than the %d byte inline limit; upload it to Cloud Storage and use AddFileURI instead", path, info.Size(), maxInlineFileSize)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %v", err)
+	}
+
+	mimeType := mimeTypeForFile(path, data)
+	gc.AddData(mimeType, data)
+	return nil
+}
+
+// mimeTypeForFile guesses the MIME type of a file from its extension,
+// falling back to sniffing its contents when the extension is unknown.
+func mimeTypeForFile(path string, data []byte) string {
+	if mimeType := mime.TypeByExtension(filepath.Ext(path)); mimeType != "" {
+		return strings.SplitN(mimeType, ";", 2)[0]
+	}
+	return http.DetectContentType(data)
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"reflect"
 	"strings"
+	"sync"
 	"time"
 
 	"cloud.google.com/go/vertexai/genai"
@@ -16,7 +17,8 @@ import (
 )
 
 type GeminiClient struct {
-	Client              *genai.Client
+	Client              *genai.Client            // The Vertex AI client. Only set when backend is the default Vertex AI backend.
+	backend             Backend                  // The provider this client talks to. Always set; defaults to a Vertex AI backend wrapping Client.
 	Functions           map[string]reflect.Value // For custom functions that the LLM can call
 	ModelName           string
 	MultiModalModelName string
@@ -28,6 +30,23 @@ type GeminiClient struct {
 	Temperature         float32
 	Trim                bool
 	Verbose             bool
+	MaxParallelCalls    int           // Maximum number of function calls to invoke concurrently within a single turn
+	MaxToolIterations   int           // Maximum number of function-call/response round trips before giving up
+	CallTimeout         time.Duration // Per-call deadline for a single dispatched function call; 0 disables it
+
+	// GenerationConfig fields applied to the model alongside Temperature.
+	// Left at their zero value, they are not sent and the model's defaults
+	// apply. Set them via SetTopP, SetTopK, and friends.
+	TopP             *float32
+	TopK             *int32
+	MaxOutputTokens  *int32
+	StopSequences    []string
+	CandidateCount   *int32
+	ResponseMIMEType string
+	ResponseSchema   *genai.Schema
+	SafetySettings   []*genai.SafetySetting
+
+	RetryPolicy RetryPolicy // How to retry transient failures from GenerateContent/SendMessage.
 }
 
 const (
@@ -40,10 +59,14 @@ const (
 	defaultMultiModalTemperature = 0.4
 	defaultTrim                  = true
 	defaultVerbose               = false
+	defaultMaxParallelCalls      = 4
+	defaultMaxToolIterations     = 8
+	defaultCallTimeout           = 30 * time.Second
 )
 
 var (
 	ErrGoogleCloudProjectID = errors.New("please set GCP_PROJECT or PROJECT_ID to your Google Cloud project ID")
+	ErrGeminiAPIKey         = errors.New("please set GEMINI_API_KEY, or pass an API key, to use the Google AI Studio backend")
 )
 
 func NewCustom(modelName, multiModalModelName, projectLocation, projectID string, temperature float32, timeout time.Duration) (*GeminiClient, error) {
@@ -59,6 +82,10 @@ func NewCustom(modelName, multiModalModelName, projectLocation, projectID string
 		Trim:                defaultTrim,
 		Verbose:             defaultVerbose,
 		Parts:               make([]genai.Part, 0),
+		MaxParallelCalls:    defaultMaxParallelCalls,
+		MaxToolIterations:   defaultMaxToolIterations,
+		CallTimeout:         defaultCallTimeout,
+		RetryPolicy:         DefaultRetryPolicy,
 	}
 	if gc.ProjectID == "" {
 		return nil, ErrGoogleCloudProjectID
@@ -73,6 +100,7 @@ func NewCustom(modelName, multiModalModelName, projectLocation, projectID string
 		return nil, fmt.Errorf("failed to create genai client: %v", err)
 	}
 	gc.Client = genaiClient
+	gc.backend = &vertexBackend{client: genaiClient}
 	return gc, nil
 }
 
@@ -116,8 +144,23 @@ func MustNewWithTimeout(modelName string, temperature float32, timeout time.Dura
 	return gc
 }
 
+// dispatchFunc invokes every call in funcalls and returns their
+// FunctionResponse parts, in the same order, so a turn's results can be
+// batched back to the model in a single SendMessage. invokeFunctions and
+// invokeFunctionsWithCallbacks are the two implementations used by
+// MultiQuery and the WithCallbacks variants respectively.
+type dispatchFunc func(ctx context.Context, funcalls []genai.FunctionCall) ([]genai.Part, error)
+
 // MultiQuery processes a prompt with optional base64-encoded data and MIME type for the data.
 func (gc *GeminiClient) MultiQuery(prompt string, base64Data, dataMimeType *string, temperature *float32) (string, error) {
+	return gc.multiQuery(prompt, base64Data, dataMimeType, temperature, gc.invokeFunctions)
+}
+
+// multiQuery is the shared implementation behind MultiQuery,
+// MultiQueryWithCallbacks, and MultiQueryWithSequentialCallbacks. It only
+// differs in how a turn's FunctionCall parts get dispatched, which is left
+// to dispatch.
+func (gc *GeminiClient) multiQuery(prompt string, base64Data, dataMimeType *string, temperature *float32, dispatch dispatchFunc) (string, error) {
 	if strings.TrimSpace(prompt) == "" {
 		return "", ErrEmptyPrompt
 	}
@@ -137,59 +180,237 @@ func (gc *GeminiClient) MultiQuery(prompt string, base64Data, dataMimeType *stri
 	ctx, cancel := context.WithTimeout(context.Background(), gc.Timeout)
 	defer cancel()
 
+	// Backends other than Vertex AI don't expose a StartChat-style session
+	// yet, so they skip the chat/function-call dance below and just
+	// generate a single response from the accumulated parts.
+	if gc.Client == nil {
+		temp := gc.Temperature
+		if temperature != nil {
+			temp = *temperature
+		}
+		cfg := gc.generationConfig(temp)
+		var result string
+		err := gc.withRetry(ctx, func() error {
+			var genErr error
+			result, genErr = gc.backend.Generate(ctx, gc.ModelName, cfg, gc.Tools, gc.Parts)
+			return genErr
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to generate content: %v", err)
+		}
+		return strings.TrimSpace(result), nil
+	}
+
 	// Set up the model with tools and start a chat session.
 	model := gc.Client.GenerativeModel(gc.ModelName)
 	if temperature != nil {
 		model.SetTemperature(*temperature)
 	}
 	model.Tools = gc.Tools
+	gc.applyGenerationConfig(model)
 	session := model.StartChat()
 
 	// Submit the multimodal query and process the result.
-	res, err := session.SendMessage(ctx, genai.Text(prompt))
+	var res *genai.GenerateContentResponse
+	err := gc.withRetry(ctx, func() error {
+		var sendErr error
+		res, sendErr = session.SendMessage(ctx, gc.Parts...)
+		return sendErr
+	})
 	if err != nil {
 		return "", fmt.Errorf("failed to send message: %v", err)
 	}
 
-	// Handle function calls if present.
+	// Handle function calls if present, looping as long as the model keeps
+	// requesting them (bounded by MaxToolIterations). A single turn may
+	// contain several FunctionCall parts; all of them are dispatched before
+	// their FunctionResponse values are batched back in one SendMessage.
+	for iteration := 0; ; iteration++ {
+		funcalls := collectFunctionCalls(res)
+		if len(funcalls) == 0 {
+			break
+		}
+		if iteration >= gc.MaxToolIterations {
+			return "", fmt.Errorf("exceeded MaxToolIterations (%d) while handling function calls", gc.MaxToolIterations)
+		}
+
+		responses, err := dispatch(ctx, funcalls)
+		if err != nil {
+			return "", fmt.Errorf("failed to handle function call: %v", err)
+		}
+
+		err = gc.withRetry(ctx, func() error {
+			var sendErr error
+			res, sendErr = session.SendMessage(ctx, responses...)
+			return sendErr
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to send function response: %v", err)
+		}
+	}
+
+	var finalResult strings.Builder
+	if len(res.Candidates) > 0 && res.Candidates[0].Content != nil {
+		for _, part := range res.Candidates[0].Content.Parts {
+			if textPart, ok := part.(genai.Text); ok {
+				finalResult.WriteString(string(textPart))
+				finalResult.WriteString("\n")
+			}
+		}
+	}
+	return strings.TrimSpace(finalResult.String()), nil
+}
+
+// collectFunctionCalls gathers every FunctionCall part found across all
+// candidates of res.
+func collectFunctionCalls(res *genai.GenerateContentResponse) []genai.FunctionCall {
+	var funcalls []genai.FunctionCall
 	for _, candidate := range res.Candidates {
+		if candidate.Content == nil {
+			continue
+		}
 		for _, part := range candidate.Content.Parts {
 			if funcall, ok := part.(genai.FunctionCall); ok {
-				// Invoke the user-defined function using reflection.
-				responseData, err := gc.invokeFunction(funcall.Name, funcall.Args)
-				if err != nil {
-					return "", fmt.Errorf("failed to handle function call: %v", err)
-				}
-
-				// Send the function response back to the model.
-				res, err = session.SendMessage(ctx, genai.FunctionResponse{
-					Name:     funcall.Name,
-					Response: responseData,
-				})
-				if err != nil {
-					return "", fmt.Errorf("failed to send function response: %v", err)
-				}
-
-				var finalResult strings.Builder
-				// Process the final response from the LLM.
-				for _, part := range res.Candidates[0].Content.Parts {
-					if textPart, ok := part.(genai.Text); ok {
-						finalResult.WriteString(string(textPart))
-						finalResult.WriteString("\n")
-					}
-				}
-				return strings.TrimSpace(finalResult.String()), nil
+				funcalls = append(funcalls, funcall)
 			}
 		}
 	}
+	return funcalls
+}
 
-	// Handle the usual case where no function call is made.
-	result, err := gc.SubmitToClient(ctx)
-	if err != nil {
-		return "", fmt.Errorf("failed to process response: %v", err)
+// callWithTimeout runs fn on its own goroutine and returns its result, or a
+// timeout error if gc.CallTimeout elapses (or ctx is canceled) first. It
+// exists because the reflection-based dispatcher and caller-supplied
+// FunctionCallHandlers are plain blocking Go functions with no context
+// parameter of their own; without it, a hung one would block wg.Wait() in
+// invokeFunctions/invokeFunctionsWithCallbacks forever. Note that fn itself
+// keeps running in the background past a timeout, since it has no way to be
+// canceled.
+func (gc *GeminiClient) callWithTimeout(ctx context.Context, fn func() (map[string]any, error)) (map[string]any, error) {
+	callCtx := ctx
+	if gc.CallTimeout > 0 {
+		var cancel context.CancelFunc
+		callCtx, cancel = context.WithTimeout(ctx, gc.CallTimeout)
+		defer cancel()
 	}
 
-	return strings.TrimSpace(result), nil
+	type result struct {
+		data map[string]any
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		data, err := fn()
+		done <- result{data, err}
+	}()
+
+	select {
+	case <-callCtx.Done():
+		return nil, callCtx.Err()
+	case r := <-done:
+		return r.data, r.err
+	}
+}
+
+// invokeFunctions dispatches every call in funcalls, running up to
+// gc.MaxParallelCalls of them concurrently via a worker pool, and returns
+// their FunctionResponse parts in the same order as funcalls. Each call is
+// bounded by gc.CallTimeout (see callWithTimeout).
+func (gc *GeminiClient) invokeFunctions(ctx context.Context, funcalls []genai.FunctionCall) ([]genai.Part, error) {
+	maxParallel := gc.MaxParallelCalls
+	if maxParallel < 1 {
+		maxParallel = 1
+	}
+
+	responses := make([]genai.Part, len(funcalls))
+	errs := make([]error, len(funcalls))
+
+	sem := make(chan struct{}, maxParallel)
+	var wg sync.WaitGroup
+	for i, funcall := range funcalls {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, funcall genai.FunctionCall) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			responseData, err := gc.callWithTimeout(ctx, func() (map[string]any, error) {
+				return gc.invokeFunction(funcall.Name, funcall.Args)
+			})
+			if err != nil {
+				errs[i] = fmt.Errorf("call to %q failed: %v", funcall.Name, err)
+				return
+			}
+			responses[i] = genai.FunctionResponse{
+				Name:     funcall.Name,
+				Response: responseData,
+			}
+		}(i, funcall)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return responses, nil
+}
+
+// FunctionCallHandler processes a single function call from the model and
+// returns the data to send back to it as a FunctionResponse.
+type FunctionCallHandler func(funcall genai.FunctionCall) (map[string]any, error)
+
+// invokeFunctionsWithCallbacks mirrors invokeFunctions, but dispatches each
+// call through callbacks[funcall.Name] (or fallback, if no entry matches
+// the call's name) instead of the reflection-based dispatcher. Like
+// invokeFunctions, up to gc.MaxParallelCalls calls run concurrently and
+// each is bounded by gc.CallTimeout.
+func (gc *GeminiClient) invokeFunctionsWithCallbacks(ctx context.Context, funcalls []genai.FunctionCall, callbacks map[string]FunctionCallHandler, fallback FunctionCallHandler) ([]genai.Part, error) {
+	maxParallel := gc.MaxParallelCalls
+	if maxParallel < 1 {
+		maxParallel = 1
+	}
+
+	responses := make([]genai.Part, len(funcalls))
+	errs := make([]error, len(funcalls))
+
+	sem := make(chan struct{}, maxParallel)
+	var wg sync.WaitGroup
+	for i, funcall := range funcalls {
+		handler, ok := callbacks[funcall.Name]
+		if !ok {
+			handler = fallback
+		}
+		if handler == nil {
+			errs[i] = fmt.Errorf("no callback registered for function %q", funcall.Name)
+			continue
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, funcall genai.FunctionCall, handler FunctionCallHandler) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			responseData, err := gc.callWithTimeout(ctx, func() (map[string]any, error) {
+				return handler(funcall)
+			})
+			if err != nil {
+				errs[i] = fmt.Errorf("call to %q failed: %v", funcall.Name, err)
+				return
+			}
+			responses[i] = genai.FunctionResponse{
+				Name:     funcall.Name,
+				Response: responseData,
+			}
+		}(i, funcall, handler)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return responses, nil
 }
 
 func (gc *GeminiClient) Query(prompt string) (string, error) {
@@ -201,11 +422,36 @@ func (gc *GeminiClient) QueryWithCallbacks(prompt string, callback FunctionCallH
 	return gc.MultiQueryWithCallbacks(prompt, nil, nil, nil, callback)
 }
 
+// MultiQueryWithCallbacks is like MultiQuery, but routes every FunctionCall
+// part through callback instead of the reflection-based dispatcher,
+// regardless of which function the model called. All FunctionCall parts in
+// a turn are dispatched concurrently, the same way MultiQuery does.
+func (gc *GeminiClient) MultiQueryWithCallbacks(prompt string, base64Data, dataMimeType *string, temperature *float32, callback FunctionCallHandler) (string, error) {
+	dispatch := func(ctx context.Context, funcalls []genai.FunctionCall) ([]genai.Part, error) {
+		return gc.invokeFunctionsWithCallbacks(ctx, funcalls, nil, callback)
+	}
+	return gc.multiQuery(prompt, base64Data, dataMimeType, temperature, dispatch)
+}
+
 // QueryWithSequentialCallbacks allows querying with a prompt and processing multiple function calls in sequence via a map of callback handlers.
 func (gc *GeminiClient) QueryWithSequentialCallbacks(prompt string, callbacks map[string]FunctionCallHandler) (string, error) {
 	return gc.MultiQueryWithSequentialCallbacks(prompt, callbacks)
 }
 
+// MultiQueryWithSequentialCallbacks is like MultiQuery, but routes every
+// FunctionCall part through callbacks[funcall.Name] instead of the
+// reflection-based dispatcher. "Sequential" refers to the turn-by-turn
+// round trip with the model, not to the calls within a single turn: when a
+// turn contains several FunctionCall parts, all of them are dispatched
+// concurrently (up to gc.MaxParallelCalls) and batched back in one
+// SendMessage, the same way MultiQuery does.
+func (gc *GeminiClient) MultiQueryWithSequentialCallbacks(prompt string, callbacks map[string]FunctionCallHandler) (string, error) {
+	dispatch := func(ctx context.Context, funcalls []genai.FunctionCall) ([]genai.Part, error) {
+		return gc.invokeFunctionsWithCallbacks(ctx, funcalls, callbacks, nil)
+	}
+	return gc.multiQuery(prompt, nil, nil, nil, dispatch)
+}
+
 func Ask(prompt string, temperature float32) (string, error) {
 	gc, err := NewWithTimeout(defaultModelName, temperature, 10*time.Second)
 	if err != nil {
@@ -237,6 +483,13 @@ func (gc *GeminiClient) SetTimeout(timeout time.Duration) {
 	gc.Timeout = timeout
 }
 
+// SetCallTimeout sets the per-call deadline applied to each dispatched
+// function call by invokeFunctions/invokeFunctionsWithCallbacks. 0 disables
+// the deadline.
+func (gc *GeminiClient) SetCallTimeout(timeout time.Duration) {
+	gc.CallTimeout = timeout
+}
+
 // SetVerbose updates the verbose logging flag of the MultiModal instance,
 // allowing for more detailed output during operations.
 func (gc *GeminiClient) SetVerbose(verbose bool) {
@@ -273,30 +526,25 @@ func (gc *GeminiClient) CountTokensWithClient(ctx context.Context) (int, error)
 	return sum, nil
 }
 
-// SubmitToClient sends all added parts to the specified Vertex AI model for processing,
-// returning the model's response. It supports temperature configuration and response trimming.
+// SubmitToClient sends all added parts to the configured model for
+// processing, returning the model's response. It supports temperature
+// configuration and response trimming, and goes through gc.backend so that
+// it works the same whether gc talks to Vertex AI or Google AI Studio.
 func (gc *GeminiClient) SubmitToClient(ctx context.Context) (result string, err error) {
 	defer func() {
 		if r := recover(); r != nil {
 			err = fmt.Errorf("panic occurred: %v", r)
 		}
 	}()
-	// Configure the model.
-	model := gc.Client.GenerativeModel(gc.ModelName)
-	model.SetTemperature(gc.Temperature)
-	// Pass in the parts and generate a response.
-	res, err := model.GenerateContent(ctx, gc.Parts...)
+	cfg := gc.generationConfig(gc.Temperature)
+	err = gc.withRetry(ctx, func() error {
+		var genErr error
+		result, genErr = gc.backend.Generate(ctx, gc.ModelName, cfg, gc.Tools, gc.Parts)
+		return genErr
+	})
 	if err != nil {
-		return "", fmt.Errorf("unable to generate contents: %v", err)
-	}
-	// Examine the response defensively.
-	if res == nil || len(res.Candidates) == 0 || res.Candidates[0] == nil ||
-		res.Candidates[0].Content == nil || res.Candidates[0].Content.Parts == nil ||
-		len(res.Candidates[0].Content.Parts) == 0 {
-		return "", errors.New("empty response from model")
+		return "", err
 	}
-	// Return the result as a string.
-	result = fmt.Sprintf("%s\n", res.Candidates[0].Content.Parts[0])
 	if gc.Trim {
 		return strings.TrimSpace(result), nil
 	}
@@ -312,15 +560,27 @@ func (gc *GeminiClient) Submit() (string, error) {
 	return gc.SubmitToClient(ctx)
 }
 
-// CountTokens creates a new client and then counts the tokens in the current multimodal prompt.
+// CountTokens counts the tokens in the current multimodal prompt, via
+// gc.backend.
 func (gc *GeminiClient) CountTokens() (int, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), gc.Timeout)
 	defer cancel()
-	return gc.CountTokensWithClient(ctx)
+	return gc.backend.CountTokens(ctx, gc.ModelName, gc.Parts)
+}
+
+// Close releases the resources held by gc's backend (the underlying Vertex
+// AI or Google AI Studio client).
+func (gc *GeminiClient) Close() error {
+	return gc.backend.Close()
 }
 
-// CountTextTokens tries to count the number of tokens in the given prompt, using the Vertex AI API.
+// CountTextTokens tries to count the number of tokens in the given prompt,
+// using the Vertex AI API. It is not supported by backends other than
+// Vertex AI yet; use CountTokens instead.
 func (gc *GeminiClient) CountTextTokens(prompt string) (int, error) {
+	if gc.Client == nil {
+		return 0, fmt.Errorf("CountTextTokens is not supported by this backend")
+	}
 	ctx, cancel := context.WithTimeout(context.Background(), gc.Timeout)
 	defer cancel()
 	return gc.CountTextTokensWithClient(ctx, gc.Client, prompt)
@@ -0,0 +1,157 @@
+package simplegemini
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/vertexai/genai"
+)
+
+func TestCollectFunctionCalls(t *testing.T) {
+	res := &genai.GenerateContentResponse{
+		Candidates: []*genai.Candidate{
+			{
+				Content: &genai.Content{
+					Parts: []genai.Part{
+						genai.Text("thinking..."),
+						genai.FunctionCall{Name: "a", Args: map[string]any{"x": 1.0}},
+						genai.FunctionCall{Name: "b", Args: map[string]any{"y": 2.0}},
+					},
+				},
+			},
+			{Content: nil},
+		},
+	}
+
+	funcalls := collectFunctionCalls(res)
+	if len(funcalls) != 2 {
+		t.Fatalf("expected 2 function calls, got %d", len(funcalls))
+	}
+	if funcalls[0].Name != "a" || funcalls[1].Name != "b" {
+		t.Fatalf("unexpected function calls: %+v", funcalls)
+	}
+}
+
+func TestCollectFunctionCallsNone(t *testing.T) {
+	res := &genai.GenerateContentResponse{
+		Candidates: []*genai.Candidate{
+			{Content: &genai.Content{Parts: []genai.Part{genai.Text("just text")}}},
+		},
+	}
+	if funcalls := collectFunctionCalls(res); len(funcalls) != 0 {
+		t.Fatalf("expected no function calls, got %d", len(funcalls))
+	}
+}
+
+func TestInvokeFunctionsWithCallbacksDispatchesAllInOrder(t *testing.T) {
+	gc := &GeminiClient{MaxParallelCalls: 4}
+	funcalls := []genai.FunctionCall{
+		{Name: "add", Args: map[string]any{"a": 1.0, "b": 2.0}},
+		{Name: "greet", Args: map[string]any{"name": "world"}},
+	}
+	callbacks := map[string]FunctionCallHandler{
+		"add": func(funcall genai.FunctionCall) (map[string]any, error) {
+			return map[string]any{"sum": 3.0}, nil
+		},
+		"greet": func(funcall genai.FunctionCall) (map[string]any, error) {
+			return map[string]any{"greeting": "hello world"}, nil
+		},
+	}
+
+	responses, err := gc.invokeFunctionsWithCallbacks(context.Background(), funcalls, callbacks, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(responses) != 2 {
+		t.Fatalf("expected 2 responses, got %d", len(responses))
+	}
+	addResp, ok := responses[0].(genai.FunctionResponse)
+	if !ok || addResp.Name != "add" {
+		t.Fatalf("unexpected first response: %+v", responses[0])
+	}
+	greetResp, ok := responses[1].(genai.FunctionResponse)
+	if !ok || greetResp.Name != "greet" {
+		t.Fatalf("unexpected second response: %+v", responses[1])
+	}
+}
+
+func TestInvokeFunctionsWithCallbacksUsesFallback(t *testing.T) {
+	gc := &GeminiClient{MaxParallelCalls: 2}
+	funcalls := []genai.FunctionCall{{Name: "unregistered"}}
+	fallback := func(funcall genai.FunctionCall) (map[string]any, error) {
+		return map[string]any{"handled": funcall.Name}, nil
+	}
+
+	responses, err := gc.invokeFunctionsWithCallbacks(context.Background(), funcalls, nil, fallback)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp, ok := responses[0].(genai.FunctionResponse)
+	if !ok || resp.Name != "unregistered" {
+		t.Fatalf("unexpected response: %+v", responses[0])
+	}
+}
+
+func TestInvokeFunctionsWithCallbacksMissingHandler(t *testing.T) {
+	gc := &GeminiClient{MaxParallelCalls: 2}
+	funcalls := []genai.FunctionCall{{Name: "unregistered"}}
+
+	if _, err := gc.invokeFunctionsWithCallbacks(context.Background(), funcalls, nil, nil); err == nil {
+		t.Fatal("expected an error for a call with no matching callback")
+	}
+}
+
+func TestInvokeFunctionsWithCallbacksRespectsCallTimeout(t *testing.T) {
+	gc := &GeminiClient{MaxParallelCalls: 2, CallTimeout: 10 * time.Millisecond}
+	funcalls := []genai.FunctionCall{{Name: "slow"}}
+	callbacks := map[string]FunctionCallHandler{
+		"slow": func(funcall genai.FunctionCall) (map[string]any, error) {
+			time.Sleep(time.Second)
+			return map[string]any{}, nil
+		},
+	}
+
+	start := time.Now()
+	_, err := gc.invokeFunctionsWithCallbacks(context.Background(), funcalls, callbacks, nil)
+	if err == nil {
+		t.Fatal("expected a timeout error for a call exceeding CallTimeout")
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Fatalf("invokeFunctionsWithCallbacks took %v, want well under CallTimeout's sleeping handler", elapsed)
+	}
+}
+
+func TestNilClientBackendGuards(t *testing.T) {
+	gc := &GeminiClient{}
+
+	if _, err := gc.NewChatSession(); err == nil {
+		t.Error("NewChatSession on a gc.Client == nil client: expected an error, got nil")
+	}
+	if _, err := gc.CountTextTokens("hello"); err == nil {
+		t.Error("CountTextTokens on a gc.Client == nil client: expected an error, got nil")
+	}
+	if _, err := gc.MultiQueryStream("hello", nil, nil, nil); err == nil {
+		t.Error("MultiQueryStream on a gc.Client == nil client: expected an error, got nil")
+	}
+}
+
+func TestInvokeFunctionsWithCallbacksPropagatesError(t *testing.T) {
+	gc := &GeminiClient{MaxParallelCalls: 2}
+	funcalls := []genai.FunctionCall{{Name: "broken"}}
+	callbacks := map[string]FunctionCallHandler{
+		"broken": func(funcall genai.FunctionCall) (map[string]any, error) {
+			return nil, errors.New("boom")
+		},
+	}
+
+	_, err := gc.invokeFunctionsWithCallbacks(context.Background(), funcalls, callbacks, nil)
+	if err == nil {
+		t.Fatal("expected an error to propagate")
+	}
+	if got := fmt.Sprint(err); got == "" {
+		t.Fatal("expected a non-empty error message")
+	}
+}
@@ -0,0 +1,130 @@
+package simplegemini
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// RetryPolicy controls how GeminiClient retries transient failures from
+// model.GenerateContent and session.SendMessage, e.g. the 429s that Vertex
+// AI's per-minute quotas produce under load.
+type RetryPolicy struct {
+	MaxAttempts    int           // Total attempts, including the first; 1 disables retrying.
+	InitialBackoff time.Duration // Delay before the first retry.
+	MaxBackoff     time.Duration // Upper bound the backoff is capped at.
+	Multiplier     float64       // Factor the backoff grows by after each retry.
+	Jitter         float64       // Fraction of the backoff to randomize, in [0, 1].
+}
+
+const (
+	defaultMaxAttempts    = 5
+	defaultInitialBackoff = 500 * time.Millisecond
+	defaultMaxBackoff     = 30 * time.Second
+	defaultMultiplier     = 2.0
+	defaultJitter         = 0.2
+)
+
+// DefaultRetryPolicy is applied to every GeminiClient constructed by this
+// package unless overridden via SetRetryPolicy.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    defaultMaxAttempts,
+	InitialBackoff: defaultInitialBackoff,
+	MaxBackoff:     defaultMaxBackoff,
+	Multiplier:     defaultMultiplier,
+	Jitter:         defaultJitter,
+}
+
+// SetRetryPolicy overrides the retry behavior used by SubmitToClient and
+// MultiQuery.
+func (gc *GeminiClient) SetRetryPolicy(policy RetryPolicy) {
+	gc.RetryPolicy = policy
+}
+
+// withRetry calls fn, retrying according to gc.RetryPolicy as long as fn
+// fails with a retryable gRPC status. It honors ctx cancellation between
+// attempts and any RetryInfo backoff hint returned in the error's details.
+func (gc *GeminiClient) withRetry(ctx context.Context, fn func() error) error {
+	policy := gc.RetryPolicy
+	if policy.MaxAttempts < 1 {
+		policy.MaxAttempts = 1
+	}
+
+	backoff := policy.InitialBackoff
+	var lastErr error
+	for attempt := 1; attempt <= policy.MaxAttempts; attempt++ {
+		lastErr = fn()
+		if lastErr == nil {
+			return nil
+		}
+		if attempt == policy.MaxAttempts || !isRetryable(lastErr) {
+			return lastErr
+		}
+
+		wait := retryInfoDelay(lastErr)
+		if wait == 0 {
+			wait = jitter(backoff, policy.Jitter)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+
+		backoff = time.Duration(float64(backoff) * policy.Multiplier)
+		if backoff > policy.MaxBackoff {
+			backoff = policy.MaxBackoff
+		}
+	}
+	return lastErr
+}
+
+// isRetryable reports whether err is a transient gRPC failure worth
+// retrying. InvalidArgument and PermissionDenied (among others) are left
+// out on purpose so they fail fast instead of burning through attempts.
+func isRetryable(err error) bool {
+	st, ok := status.FromError(err)
+	if !ok {
+		return false
+	}
+	switch st.Code() {
+	case codes.ResourceExhausted, codes.Unavailable, codes.DeadlineExceeded, codes.Internal:
+		return true
+	default:
+		return false
+	}
+}
+
+// retryInfoDelay extracts the server-suggested backoff from a RetryInfo
+// error detail, if present, or 0 if there is none.
+func retryInfoDelay(err error) time.Duration {
+	st, ok := status.FromError(err)
+	if !ok {
+		return 0
+	}
+	for _, detail := range st.Details() {
+		if retryInfo, ok := detail.(*errdetails.RetryInfo); ok && retryInfo.RetryDelay != nil {
+			return retryInfo.RetryDelay.AsDuration()
+		}
+	}
+	return 0
+}
+
+// jitter randomizes d by up to fraction (a value in [0, 1]) in either
+// direction.
+func jitter(d time.Duration, fraction float64) time.Duration {
+	if fraction <= 0 {
+		return d
+	}
+	delta := float64(d) * fraction
+	offset := (rand.Float64()*2 - 1) * delta
+	result := time.Duration(float64(d) + offset)
+	if result < 0 {
+		return 0
+	}
+	return result
+}
@@ -0,0 +1,78 @@
+package simplegemini
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func TestIsRetryable(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"non-grpc error", errors.New("boom"), false},
+		{"resource exhausted", status.Error(codes.ResourceExhausted, "quota"), true},
+		{"unavailable", status.Error(codes.Unavailable, "down"), true},
+		{"deadline exceeded", status.Error(codes.DeadlineExceeded, "slow"), true},
+		{"internal", status.Error(codes.Internal, "oops"), true},
+		{"invalid argument", status.Error(codes.InvalidArgument, "bad"), false},
+		{"permission denied", status.Error(codes.PermissionDenied, "no"), false},
+	}
+	for _, c := range cases {
+		if got := isRetryable(c.err); got != c.want {
+			t.Errorf("isRetryable(%s) = %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestRetryInfoDelay(t *testing.T) {
+	if got := retryInfoDelay(errors.New("not a grpc error")); got != 0 {
+		t.Errorf("retryInfoDelay(non-grpc error) = %v, want 0", got)
+	}
+	if got := retryInfoDelay(status.Error(codes.Unavailable, "down")); got != 0 {
+		t.Errorf("retryInfoDelay(no RetryInfo detail) = %v, want 0", got)
+	}
+
+	st, err := status.New(codes.ResourceExhausted, "quota").WithDetails(&errdetails.RetryInfo{
+		RetryDelay: durationpb.New(2 * time.Second),
+	})
+	if err != nil {
+		t.Fatalf("failed to attach RetryInfo detail: %v", err)
+	}
+	if got := retryInfoDelay(st.Err()); got != 2*time.Second {
+		t.Errorf("retryInfoDelay(with RetryInfo) = %v, want %v", got, 2*time.Second)
+	}
+}
+
+func TestJitter(t *testing.T) {
+	if got := jitter(time.Second, 0); got != time.Second {
+		t.Errorf("jitter(1s, 0) = %v, want %v (no fraction, no change)", got, time.Second)
+	}
+
+	d := time.Second
+	fraction := 0.2
+	lo := time.Duration(float64(d) * (1 - fraction))
+	hi := time.Duration(float64(d) * (1 + fraction))
+	for i := 0; i < 100; i++ {
+		got := jitter(d, fraction)
+		if got < lo || got > hi {
+			t.Fatalf("jitter(%v, %v) = %v, want within [%v, %v]", d, fraction, got, lo, hi)
+		}
+	}
+
+	// With a huge fraction, about half the draws pull the result below zero;
+	// those must be clamped to 0 rather than returned negative.
+	for i := 0; i < 100; i++ {
+		if got := jitter(time.Millisecond, 1e9); got < 0 {
+			t.Fatalf("jitter with huge fraction pulling negative = %v, want >= 0 (clamped)", got)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package simplegemini
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"cloud.google.com/go/vertexai/genai"
+	"google.golang.org/api/iterator"
+)
+
+// ChunkHandler is called once per partial text chunk received while streaming.
+// Returning a non-nil error aborts the stream.
+type ChunkHandler func(chunk string) error
+
+// QueryStream submits the given prompt to the model and invokes onChunk for
+// every partial piece of text as it arrives, instead of waiting for the full
+// response like Query/SubmitToClient do. It respects gc.Timeout via context
+// cancellation and returns the fully assembled response once the stream ends.
+func (gc *GeminiClient) QueryStream(prompt string, onChunk ChunkHandler) (string, error) {
+	return gc.MultiQueryStream(prompt, nil, nil, onChunk)
+}
+
+// MultiQueryStream is the streaming counterpart of MultiQuery. It decodes the
+// optional base64-encoded data, adds it alongside the prompt, then drives a
+// chat session with SendMessageStream instead of GenerateContent, calling
+// onChunk for each partial text part as it is received. If the model
+// responds with one or more FunctionCall parts once a turn's stream ends,
+// all of them are dispatched (concurrently, up to gc.MaxParallelCalls, the
+// same way MultiQuery and ChatSession.SendMultimodal do) and the resulting
+// FunctionResponse values are streamed back in, looping until the model
+// stops asking for more calls or MaxToolIterations is reached. It is not
+// supported by backends other than Vertex AI yet.
+func (gc *GeminiClient) MultiQueryStream(prompt string, base64Data, dataMimeType *string, onChunk ChunkHandler) (string, error) {
+	if strings.TrimSpace(prompt) == "" {
+		return "", ErrEmptyPrompt
+	}
+	if gc.Client == nil {
+		return "", fmt.Errorf("streaming is not supported by this backend")
+	}
+
+	gc.ClearParts()
+	gc.AddText(prompt)
+
+	if base64Data != nil && dataMimeType != nil {
+		data, err := base64.StdEncoding.DecodeString(*base64Data)
+		if err != nil {
+			return "", fmt.Errorf("failed to decode base64 data: %v", err)
+		}
+		gc.AddData(*dataMimeType, data)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), gc.Timeout)
+	defer cancel()
+
+	model := gc.Client.GenerativeModel(gc.ModelName)
+	model.SetTemperature(gc.Temperature)
+	model.Tools = gc.Tools
+	gc.applyGenerationConfig(model)
+	session := model.StartChat()
+
+	var result strings.Builder
+	parts := gc.Parts
+	for iteration := 0; ; iteration++ {
+		funcalls, err := streamChunks(session.SendMessageStream(ctx, parts...), onChunk, &result)
+		if err != nil {
+			return "", err
+		}
+		if len(funcalls) == 0 {
+			break
+		}
+		if iteration >= gc.MaxToolIterations {
+			return "", fmt.Errorf("exceeded MaxToolIterations (%d) while handling function calls", gc.MaxToolIterations)
+		}
+
+		responses, err := gc.invokeFunctions(ctx, funcalls)
+		if err != nil {
+			return "", fmt.Errorf("failed to handle streamed function call: %v", err)
+		}
+		parts = responses
+	}
+
+	if gc.Trim {
+		return strings.TrimSpace(result.String()), nil
+	}
+	return result.String(), nil
+}
+
+// streamChunks drains iter, writing every text part to result (and onChunk,
+// if non-nil) as it arrives, and returns any FunctionCall parts found once
+// the stream ends.
+func streamChunks(iter *genai.GenerateContentResponseIterator, onChunk ChunkHandler, result *strings.Builder) ([]genai.FunctionCall, error) {
+	var funcalls []genai.FunctionCall
+	for {
+		res, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to stream content: %v", err)
+		}
+		if res == nil || len(res.Candidates) == 0 || res.Candidates[0].Content == nil {
+			continue
+		}
+		for _, part := range res.Candidates[0].Content.Parts {
+			switch p := part.(type) {
+			case genai.Text:
+				chunk := string(p)
+				result.WriteString(chunk)
+				if onChunk != nil {
+					if err := onChunk(chunk); err != nil {
+						return nil, fmt.Errorf("chunk handler failed: %v", err)
+					}
+				}
+			case genai.FunctionCall:
+				funcalls = append(funcalls, p)
+			}
+		}
+	}
+	return funcalls, nil
+}